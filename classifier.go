@@ -0,0 +1,350 @@
+package gpmctl
+
+import (
+	"sync"
+	"time"
+)
+
+// GestureKind identifies what a Gesture represents.
+type GestureKind int
+
+const (
+	Click GestureKind = iota
+	DoubleClick
+	TripleClick
+	// LongPress is classified at release time, once a held button has
+	// passed Classifier.LongPressAfter without dragging: the pull-based
+	// Next() model only observes an event when one arrives, so there is
+	// no way to emit it while the button is still down without a timer
+	// running independently of Read. A caller that needs the press-time
+	// signal has to poll or add its own timer around Next().
+	LongPress
+	DragStart
+	DragMove
+	DragEnd
+	WheelScroll
+	Swipe
+)
+
+func (k GestureKind) String() string {
+	switch k {
+	case Click:
+		return "click"
+	case DoubleClick:
+		return "double-click"
+	case TripleClick:
+		return "triple-click"
+	case LongPress:
+		return "long-press"
+	case DragStart:
+		return "drag-start"
+	case DragMove:
+		return "drag-move"
+	case DragEnd:
+		return "drag-end"
+	case WheelScroll:
+		return "wheel-scroll"
+	case Swipe:
+		return "swipe"
+	default:
+		return "unknown"
+	}
+}
+
+// Direction is the dominant axis of a Swipe.
+type Direction int
+
+const (
+	DirUp Direction = iota
+	DirDown
+	DirLeft
+	DirRight
+)
+
+// Gesture is a higher-level interpretation of one or more raw Event's,
+// as produced by a Classifier. Not every field is meaningful for every
+// Kind, see the comments on each field.
+type Gesture struct {
+	Kind    GestureKind
+	Button  Buttons
+	X, Y    int16 // position the gesture completed/is currently at
+	DX, DY  int16 // DragStart/DragMove/DragEnd/Swipe: offset from the drag's origin
+	WheelDX int32 // WheelScroll: whole notches scrolled horizontally
+	WheelDY int32 // WheelScroll: whole notches scrolled vertically
+	// Momentum is set on a WheelScroll that arrived soon enough after
+	// the previous one to look like inertial/trackpad scrolling rather
+	// than a single discrete wheel click.
+	Momentum  bool
+	Direction Direction // Swipe only
+}
+
+// eventReader is the minimal surface Classifier needs from a GPM; a
+// plain *GPM satisfies it.
+type eventReader interface {
+	Read() (Event, error)
+}
+
+type buttonTrack struct {
+	down      bool
+	downAt    time.Time
+	downX     int16
+	downY     int16
+	dragging  bool
+	lastUpAt  time.Time
+	streak    int32
+	hasLastUp bool
+}
+
+// Classifier sits on top of a GPM (or any eventReader, e.g. an
+// evdevSource wrapped by a GPM) and turns its raw Event stream into
+// Gesture's: clicks, drags and wheel scrolls debounced/thresholded in Go,
+// independent of GPM's own (and evdev's entirely absent) click counting.
+type Classifier struct {
+	src eventReader
+
+	// ClickWindow is how close together in time two button-up events
+	// for the same button must land to count as a double/triple click.
+	// Defaults to 300ms.
+	ClickWindow time.Duration
+	// LongPressAfter is how long a button must be held, without
+	// dragging, before releasing it is reported as LongPress instead of
+	// a Click. Defaults to 500ms.
+	LongPressAfter time.Duration
+	// DragThreshold is how many pixels the pointer must move while a
+	// button is held before it counts as a drag rather than a click.
+	// Defaults to 4.
+	DragThreshold int16
+	// SwipeMaxDuration and SwipeThreshold gate whether a completed drag
+	// is reported as a Swipe instead of a DragEnd: the drag must cover
+	// at least SwipeThreshold pixels in under SwipeMaxDuration.
+	// Defaults to 300ms and 40px.
+	SwipeMaxDuration time.Duration
+	SwipeThreshold   int16
+	// WheelUnitsPerNotch scales raw WDX/WDY units into whole scroll
+	// notches, e.g. 120 for hi-res wheels that report in 120ths of a
+	// notch. Defaults to 1, i.e. gpm/evdev deltas are already notches.
+	WheelUnitsPerNotch int32
+	// MomentumWindow: a WheelScroll arriving within this long of the
+	// previous one is flagged as Momentum.
+	MomentumWindow time.Duration
+
+	mu          sync.Mutex
+	track       map[Buttons]*buttonTrack
+	pressed     Buttons // button latched at the last DOWN, see resolveButton
+	wheelAccX   int32
+	wheelAccY   int32
+	lastWheelAt time.Time
+}
+
+// NewClassifier wraps src (typically a *GPM) with repo-standard
+// defaults for click/drag/wheel timing.
+func NewClassifier(src eventReader) *Classifier {
+	return &Classifier{
+		src:                src,
+		ClickWindow:        300 * time.Millisecond,
+		LongPressAfter:     500 * time.Millisecond,
+		DragThreshold:      4,
+		SwipeMaxDuration:   300 * time.Millisecond,
+		SwipeThreshold:     40,
+		WheelUnitsPerNotch: 1,
+		MomentumWindow:     50 * time.Millisecond,
+		track:              map[Buttons]*buttonTrack{},
+	}
+}
+
+// Next reads and classifies raw events until one of them produces a
+// Gesture, then returns it. It blocks, the same way GPM.Read does.
+func (c *Classifier) Next() (Gesture, error) {
+	for {
+		event, err := c.src.Read()
+		if err != nil {
+			return Gesture{}, err
+		}
+		if g, ok := c.classify(event); ok {
+			return g, nil
+		}
+	}
+}
+
+func (c *Classifier) classify(e Event) (Gesture, bool) {
+	// Wheel movement is reported with Buttons==B_NONE and must not
+	// disturb any button's click timer, so it's handled entirely
+	// separately from the button state machines below.
+	if e.WDX != 0 || e.WDY != 0 {
+		return c.classifyWheel(e)
+	}
+
+	switch {
+	case e.Type&DOWN > 0:
+		c.onDown(e)
+		return Gesture{}, false
+	case e.Type&(DRAG|MOVE) > 0:
+		return c.onMove(e)
+	case e.Type&UP > 0:
+		return c.onUp(e)
+	}
+	return Gesture{}, false
+}
+
+// resolveButton identifies which button an event not reliably reporting
+// Buttons belongs to. gpm doesn't always populate it (the package doc's
+// own recorded sample shows "down,single[buttons:, ...]" and
+// "drag,single,mflag[buttons:, ...]" with an empty Buttons field), so
+// DRAG/MOVE/UP fall back to whatever DOWN last latched via c.pressed
+// rather than trusting Buttons on every frame. Must be called with c.mu
+// held.
+func (c *Classifier) resolveButton(e Event) Buttons {
+	if e.Buttons != B_NONE {
+		return e.Buttons
+	}
+	return c.pressed
+}
+
+func (c *Classifier) track_(b Buttons) *buttonTrack {
+	t := c.track[b]
+	if t == nil {
+		t = &buttonTrack{}
+		c.track[b] = t
+	}
+	return t
+}
+
+func (c *Classifier) onDown(e Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	btn := e.Buttons
+	if btn == B_NONE {
+		btn = B_LEFT
+	}
+	c.pressed = btn
+
+	t := c.track_(btn)
+	t.down = true
+	t.downAt = time.Now()
+	t.downX, t.downY = e.X, e.Y
+	t.dragging = false
+}
+
+func (c *Classifier) onMove(e Event) (Gesture, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	btn := c.resolveButton(e)
+	t := c.track[btn]
+	if t == nil || !t.down {
+		return Gesture{}, false
+	}
+
+	if !t.dragging {
+		if abs16(e.X-t.downX) < c.DragThreshold && abs16(e.Y-t.downY) < c.DragThreshold {
+			return Gesture{}, false
+		}
+		t.dragging = true
+		return Gesture{Kind: DragStart, Button: btn, X: e.X, Y: e.Y, DX: e.X - t.downX, DY: e.Y - t.downY}, true
+	}
+	return Gesture{Kind: DragMove, Button: btn, X: e.X, Y: e.Y, DX: e.X - t.downX, DY: e.Y - t.downY}, true
+}
+
+func (c *Classifier) onUp(e Event) (Gesture, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	btn := c.resolveButton(e)
+	c.pressed = B_NONE
+
+	t := c.track_(btn)
+	now := time.Now()
+	wasDragging := t.dragging
+	downAt, downX, downY := t.downAt, t.downX, t.downY
+	t.down = false
+	t.dragging = false
+
+	if wasDragging {
+		dx, dy := e.X-downX, e.Y-downY
+		if now.Sub(downAt) <= c.SwipeMaxDuration && (abs16(dx) >= c.SwipeThreshold || abs16(dy) >= c.SwipeThreshold) {
+			return Gesture{Kind: Swipe, Button: btn, X: e.X, Y: e.Y, DX: dx, DY: dy, Direction: swipeDirection(dx, dy)}, true
+		}
+		return Gesture{Kind: DragEnd, Button: btn, X: e.X, Y: e.Y, DX: dx, DY: dy}, true
+	}
+
+	if now.Sub(downAt) >= c.LongPressAfter {
+		t.streak = 0
+		t.hasLastUp = false
+		return Gesture{Kind: LongPress, Button: btn, X: e.X, Y: e.Y}, true
+	}
+
+	if t.hasLastUp && now.Sub(t.lastUpAt) <= c.ClickWindow {
+		t.streak++
+	} else {
+		t.streak = 1
+	}
+	if t.streak > 3 {
+		t.streak = 3
+	}
+	t.lastUpAt = now
+	t.hasLastUp = true
+
+	kind := Click
+	switch t.streak {
+	case 2:
+		kind = DoubleClick
+	case 3:
+		kind = TripleClick
+	}
+	return Gesture{Kind: kind, Button: btn, X: e.X, Y: e.Y}, true
+}
+
+func (c *Classifier) classifyWheel(e Event) (Gesture, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	unit := c.WheelUnitsPerNotch
+	if unit <= 0 {
+		unit = 1
+	}
+
+	c.wheelAccX += int32(e.WDX)
+	c.wheelAccY += int32(e.WDY)
+	notchesX := c.wheelAccX / unit
+	notchesY := c.wheelAccY / unit
+	c.wheelAccX -= notchesX * unit
+	c.wheelAccY -= notchesY * unit
+
+	if notchesX == 0 && notchesY == 0 {
+		return Gesture{}, false
+	}
+
+	now := time.Now()
+	momentum := !c.lastWheelAt.IsZero() && now.Sub(c.lastWheelAt) <= c.MomentumWindow
+	c.lastWheelAt = now
+
+	return Gesture{
+		Kind:     WheelScroll,
+		X:        e.X,
+		Y:        e.Y,
+		WheelDX:  notchesX,
+		WheelDY:  notchesY,
+		Momentum: momentum,
+	}, true
+}
+
+func swipeDirection(dx, dy int16) Direction {
+	if abs16(dx) > abs16(dy) {
+		if dx > 0 {
+			return DirRight
+		}
+		return DirLeft
+	}
+	if dy > 0 {
+		return DirDown
+	}
+	return DirUp
+}
+
+func abs16(v int16) int16 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
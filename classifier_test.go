@@ -0,0 +1,138 @@
+package gpmctl
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestClassifier() *Classifier {
+	c := NewClassifier(nil)
+	c.LongPressAfter = time.Hour // keep click tests away from the long-press path
+	return c
+}
+
+func TestClassifierClickDoubleTriple(t *testing.T) {
+	c := newTestClassifier()
+
+	c.classify(Event{Type: DOWN, Buttons: B_LEFT, X: 5, Y: 5})
+	g, ok := c.classify(Event{Type: UP, Buttons: B_LEFT, X: 5, Y: 5})
+	if !ok || g.Kind != Click {
+		t.Fatalf("first up: ok=%v kind=%v, want Click", ok, g.Kind)
+	}
+
+	c.classify(Event{Type: DOWN, Buttons: B_LEFT, X: 5, Y: 5})
+	g, ok = c.classify(Event{Type: UP, Buttons: B_LEFT, X: 5, Y: 5})
+	if !ok || g.Kind != DoubleClick {
+		t.Fatalf("second up within window: ok=%v kind=%v, want DoubleClick", ok, g.Kind)
+	}
+
+	c.classify(Event{Type: DOWN, Buttons: B_LEFT, X: 5, Y: 5})
+	g, ok = c.classify(Event{Type: UP, Buttons: B_LEFT, X: 5, Y: 5})
+	if !ok || g.Kind != TripleClick {
+		t.Fatalf("third up within window: ok=%v kind=%v, want TripleClick", ok, g.Kind)
+	}
+}
+
+func TestClassifierClickStreakResetsAfterWindow(t *testing.T) {
+	c := newTestClassifier()
+	c.ClickWindow = 5 * time.Millisecond
+
+	c.classify(Event{Type: DOWN, Buttons: B_LEFT})
+	c.classify(Event{Type: UP, Buttons: B_LEFT})
+	time.Sleep(20 * time.Millisecond)
+
+	c.classify(Event{Type: DOWN, Buttons: B_LEFT})
+	g, ok := c.classify(Event{Type: UP, Buttons: B_LEFT})
+	if !ok || g.Kind != Click {
+		t.Fatalf("up after window elapsed: ok=%v kind=%v, want Click", ok, g.Kind)
+	}
+}
+
+func TestClassifierDragStartMoveEnd(t *testing.T) {
+	c := newTestClassifier()
+
+	c.classify(Event{Type: DOWN, Buttons: B_LEFT, X: 0, Y: 0})
+	if _, ok := c.classify(Event{Type: DRAG, Buttons: B_LEFT, X: 1, Y: 0}); ok {
+		t.Fatalf("move under DragThreshold should not classify")
+	}
+
+	g, ok := c.classify(Event{Type: DRAG, Buttons: B_LEFT, X: 10, Y: 0})
+	if !ok || g.Kind != DragStart || g.DX != 10 {
+		t.Fatalf("drag start: ok=%v g=%+v, want DragStart DX=10", ok, g)
+	}
+
+	g, ok = c.classify(Event{Type: DRAG, Buttons: B_LEFT, X: 15, Y: 0})
+	if !ok || g.Kind != DragMove || g.DX != 15 {
+		t.Fatalf("drag move: ok=%v g=%+v, want DragMove DX=15 (offset from origin, not incremental)", ok, g)
+	}
+
+	g, ok = c.classify(Event{Type: UP, Buttons: B_LEFT, X: 20, Y: 0})
+	if !ok || g.Kind != DragEnd || g.DX != 20 {
+		t.Fatalf("drag end: ok=%v g=%+v, want DragEnd DX=20", ok, g)
+	}
+}
+
+func TestClassifierSwipe(t *testing.T) {
+	c := newTestClassifier()
+	c.SwipeThreshold = 10
+	c.SwipeMaxDuration = time.Second
+
+	c.classify(Event{Type: DOWN, Buttons: B_LEFT, X: 0, Y: 0})
+	c.classify(Event{Type: DRAG, Buttons: B_LEFT, X: 50, Y: 0})
+	g, ok := c.classify(Event{Type: UP, Buttons: B_LEFT, X: 50, Y: 0})
+	if !ok || g.Kind != Swipe || g.Direction != DirRight {
+		t.Fatalf("fast long drag: ok=%v g=%+v, want Swipe DirRight", ok, g)
+	}
+}
+
+func TestClassifierLongPress(t *testing.T) {
+	c := newTestClassifier()
+	c.LongPressAfter = 5 * time.Millisecond
+
+	c.classify(Event{Type: DOWN, Buttons: B_LEFT})
+	time.Sleep(20 * time.Millisecond)
+	g, ok := c.classify(Event{Type: UP, Buttons: B_LEFT})
+	if !ok || g.Kind != LongPress {
+		t.Fatalf("up after LongPressAfter elapsed without dragging: ok=%v kind=%v, want LongPress", ok, g.Kind)
+	}
+}
+
+// gpm's package doc records real output where down/drag/up events all
+// carry an empty Buttons field. The classifier must still latch the
+// button DOWN identified and key the drag/click state machines off it,
+// not off Buttons on every subsequent frame.
+func TestClassifierDragAndClickWithEmptyButtons(t *testing.T) {
+	c := newTestClassifier()
+
+	c.classify(Event{Type: DOWN | SINGLE, X: 0, Y: 0})
+	g, ok := c.classify(Event{Type: DRAG, X: 10, Y: 0})
+	if !ok || g.Kind != DragStart || g.Button != B_LEFT {
+		t.Fatalf("drag start with empty Buttons: ok=%v g=%+v, want DragStart latched to B_LEFT", ok, g)
+	}
+
+	g, ok = c.classify(Event{Type: UP, X: 10, Y: 0})
+	if !ok || g.Kind != DragEnd {
+		t.Fatalf("drag end with empty Buttons: ok=%v g=%+v, want DragEnd", ok, g)
+	}
+}
+
+func TestClassifierWheelNotchesAndMomentum(t *testing.T) {
+	c := newTestClassifier()
+	c.WheelUnitsPerNotch = 120
+	c.MomentumWindow = time.Second
+
+	g, ok := c.classify(Event{WDY: 119})
+	if ok {
+		t.Fatalf("partial notch should not yet classify, got %+v", g)
+	}
+
+	g, ok = c.classify(Event{WDY: 1})
+	if !ok || g.Kind != WheelScroll || g.WheelDY != 1 || g.Momentum {
+		t.Fatalf("first full notch: ok=%v g=%+v, want WheelScroll WheelDY=1 Momentum=false", ok, g)
+	}
+
+	g, ok = c.classify(Event{WDY: 120})
+	if !ok || g.WheelDY != 1 || !g.Momentum {
+		t.Fatalf("notch arriving inside MomentumWindow: ok=%v g=%+v, want Momentum=true", ok, g)
+	}
+}
@@ -0,0 +1,80 @@
+package gpmctl
+
+import (
+	"context"
+	"time"
+)
+
+// deadlineSource is a Source that can have its blocking Read interrupted
+// by arming a deadline, as net.Conn and os.File both support.
+type deadlineSource interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// ReadContext is Read, cancellable via ctx. It's implemented by arming a
+// read deadline on the underlying Source and racing a goroutine that
+// watches ctx.Done() to bring that deadline forward, so a blocked Read
+// returns promptly once ctx is canceled.
+//
+// The deadline can interrupt Source.Read partway through a frame: the
+// bytes already consumed are discarded, ReadContext returns ctx.Err(),
+// and the byte stream is out of step from there on. Set GPMConnect.Magic
+// to make the connection self-resynchronizing across that kind of torn
+// read.
+func (g *GPM) ReadContext(ctx context.Context) (Event, error) {
+	if dl, ok := g.src.(deadlineSource); ok {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				dl.SetReadDeadline(time.Now())
+			case <-done:
+			}
+		}()
+		defer dl.SetReadDeadline(time.Time{})
+	}
+
+	event, err := g.Read()
+	if err != nil && ctx.Err() != nil {
+		return Event{}, ctx.Err()
+	}
+	return event, err
+}
+
+// Stream starts a goroutine reading events off g and publishes them on
+// the returned channel, which is closed once ctx is canceled or Read
+// returns an error. Unlike calling ReadContext in a loop, it arms a
+// single long-lived cancellation watcher for the whole stream instead of
+// one per event.
+func (g *GPM) Stream(ctx context.Context) <-chan Event {
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+
+		if dl, ok := g.src.(deadlineSource); ok {
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				select {
+				case <-ctx.Done():
+					dl.SetReadDeadline(time.Now())
+				case <-done:
+				}
+			}()
+		}
+
+		for {
+			event, err := g.Read()
+			if err != nil {
+				return
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
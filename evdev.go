@@ -0,0 +1,340 @@
+//go:build linux
+
+package gpmctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// struct input_event as per linux/input.h:
+//
+//	struct input_event {
+//	  struct timeval time; /* two longs: tv_sec, tv_usec */
+//	  __u16 type;
+//	  __u16 code;
+//	  __s32 value;
+//	};
+//
+// On 64bit time_t platforms (everything but the x32 ABI) the two longs
+// are 8 bytes each, giving a 24 byte struct; on the few platforms that
+// still use a 32bit time_t it's 16 bytes. `long` and `time_t` are the
+// native word size on every architecture Go's input_event ABI matters
+// on, so unsafe.Sizeof(uintptr(0)) tells them apart: 4 bytes there means
+// a 32bit time_t.
+const (
+	inputEventSize64 = 24
+	inputEventSize32 = 16
+)
+
+func nativeInputEventSize() int {
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		return inputEventSize32
+	}
+	return inputEventSize64
+}
+
+const (
+	evSyn = 0x00
+	evKey = 0x01
+	evRel = 0x02
+)
+
+const synReport = 0
+
+const (
+	relX      = 0x00
+	relY      = 0x01
+	relHWheel = 0x06
+	relWheel  = 0x08
+)
+
+const (
+	btnLeft   = 0x110
+	btnRight  = 0x111
+	btnMiddle = 0x112
+	btnSide   = 0x113
+)
+
+// DefaultClickThreshold is how close together in time two button-down
+// events for the same button must be to count as a double/triple click,
+// used when an evdevSource isn't given an explicit one. Classifier does
+// not read it; it has its own ClickWindow.
+const DefaultClickThreshold = 300 * time.Millisecond
+
+// evdevSource reads raw mouse events off a /dev/input/eventX device and
+// synthesizes the same Event's gpmctlSource produces from the gpm
+// daemon, since evdev itself knows nothing about gpm's clicks/margin/Roi
+// concepts.
+type evdevSource struct {
+	f         *os.File
+	eventSize int
+
+	mu             sync.Mutex
+	x, y           int16
+	buttons        Buttons
+	clickThreshold time.Duration
+	lastDownAt     map[Buttons]time.Time
+	streak         map[Buttons]int32
+}
+
+// NewFromEvdev opens the first device in paths that exposes REL_X and
+// REL_Y plus at least one of BTN_LEFT/BTN_RIGHT/BTN_MIDDLE (i.e. a
+// mouse) and reads events off it directly,
+// without involving the gpm daemon. If paths is empty, every
+// /dev/input/event* device is tried.
+func NewFromEvdev(paths ...string) (*GPM, error) {
+	if len(paths) == 0 {
+		matches, err := filepath.Glob("/dev/input/event*")
+		if err != nil {
+			return nil, err
+		}
+		paths = matches
+	}
+
+	var lastErr error
+	for _, p := range paths {
+		src, err := openEvdevSource(p)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return &GPM{src: src}, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("gpmctl: no evdev device given")
+	}
+	return nil, fmt.Errorf("gpmctl: no usable mouse found in %v: %w", paths, lastErr)
+}
+
+func openEvdevSource(path string) (*evdevSource, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	relBits, err := ioctlEviocgbit(f.Fd(), evRel, 1)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !testBit(relBits, relX) || !testBit(relBits, relY) {
+		f.Close()
+		return nil, fmt.Errorf("gpmctl: %s has no REL_X/REL_Y, not a mouse", path)
+	}
+
+	keyBits, err := ioctlEviocgbit(f.Fd(), evKey, (btnSide/8)+1)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !testBit(keyBits, btnLeft) && !testBit(keyBits, btnRight) && !testBit(keyBits, btnMiddle) {
+		f.Close()
+		return nil, fmt.Errorf("gpmctl: %s has no mouse buttons", path)
+	}
+
+	return &evdevSource{
+		f:              f,
+		eventSize:      nativeInputEventSize(),
+		clickThreshold: DefaultClickThreshold,
+		lastDownAt:     map[Buttons]time.Time{},
+		streak:         map[Buttons]int32{},
+	}, nil
+}
+
+// SetClickThreshold overrides the default 300ms double/triple click
+// window used when synthesizing click counts from raw evdev events.
+func (s *evdevSource) SetClickThreshold(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clickThreshold = d
+}
+
+func (s *evdevSource) Close() error {
+	return s.f.Close()
+}
+
+func (s *evdevSource) SetReadDeadline(t time.Time) error {
+	return s.f.SetReadDeadline(t)
+}
+
+// Read accumulates REL_X/REL_Y/REL_WHEEL/REL_HWHEEL and BTN_* transitions
+// until SYN_REPORT, then synthesizes one Event the way gpm's own mouse
+// drivers would, including Go-side click/double-click/triple-click
+// timing since evdev has no click counter of its own.
+//
+// If interrupted mid-gesture (e.g. via GPM.ReadContext after a BTN_* down
+// but before the terminating SYN_REPORT), any button transition already
+// folded into s.buttons is kept but never delivered as an Event, so the
+// next successful Read may report an UP with no corresponding DOWN.
+func (s *evdevSource) Read() (Event, error) {
+	var dx, dy, wdx, wdy int16
+	var downed, upped Buttons
+
+	for {
+		typ, code, value, err := s.readRaw()
+		if err != nil {
+			return Event{}, err
+		}
+
+		switch typ {
+		case evRel:
+			switch code {
+			case relX:
+				dx += int16(value)
+			case relY:
+				dy += int16(value)
+			case relWheel:
+				wdy += int16(value)
+			case relHWheel:
+				wdx += int16(value)
+			}
+		case evKey:
+			b := buttonFor(code)
+			if b == B_NONE {
+				continue
+			}
+			s.mu.Lock()
+			if value != 0 {
+				s.buttons |= b
+				downed |= b
+			} else {
+				s.buttons &^= b
+				upped |= b
+			}
+			s.mu.Unlock()
+		case evSyn:
+			if code != synReport {
+				continue
+			}
+			if dx == 0 && dy == 0 && wdx == 0 && wdy == 0 && downed == B_NONE && upped == B_NONE {
+				continue
+			}
+			return s.synReport(dx, dy, wdx, wdy, downed, upped), nil
+		}
+	}
+}
+
+func (s *evdevSource) synReport(dx, dy, wdx, wdy int16, downed, upped Buttons) Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.x += dx
+	s.y += dy
+
+	e := Event{
+		Buttons: s.buttons,
+		DX:      dx,
+		DY:      dy,
+		X:       s.x,
+		Y:       s.y,
+		WDX:     wdx,
+		WDY:     wdy,
+	}
+
+	now := time.Now()
+	switch {
+	case downed != B_NONE:
+		streak := s.streak[downed] + 1
+		if last, ok := s.lastDownAt[downed]; !ok || now.Sub(last) > s.clickThreshold {
+			streak = 1
+		}
+		if streak > 3 {
+			streak = 3
+		}
+		s.streak[downed] = streak
+		s.lastDownAt[downed] = now
+		e.Clicks = streak
+		e.Type = DOWN | clickBit(streak)
+	case upped != B_NONE:
+		e.Clicks = s.streak[upped]
+		e.Type = UP | clickBit(s.streak[upped])
+	case s.buttons != B_NONE && (dx != 0 || dy != 0):
+		e.Type = DRAG
+	case dx != 0 || dy != 0 || wdx != 0 || wdy != 0:
+		e.Type = MOVE
+	}
+	return e
+}
+
+func clickBit(streak int32) EventType {
+	switch streak {
+	case 2:
+		return DOUBLE
+	case 3:
+		return TRIPLE
+	default:
+		return SINGLE
+	}
+}
+
+func buttonFor(code uint16) Buttons {
+	switch code {
+	case btnLeft:
+		return B_LEFT
+	case btnMiddle:
+		return B_MIDDLE
+	case btnRight:
+		return B_RIGHT
+	case btnSide:
+		return B_FOURTH
+	default:
+		return B_NONE
+	}
+}
+
+func (s *evdevSource) readRaw() (typ, code uint16, value int32, err error) {
+	b := make([]byte, s.eventSize)
+	if _, err := io.ReadFull(s.f, b); err != nil {
+		return 0, 0, 0, err
+	}
+	// the timeval prefix is irrelevant to us, type/code/value sit right
+	// before the end of the struct regardless of timeval's width.
+	o := s.eventSize - 8
+	typ = nativeEndian.Uint16(b[o:])
+	code = nativeEndian.Uint16(b[o+2:])
+	value = int32(nativeEndian.Uint32(b[o+4:]))
+	return typ, code, value, nil
+}
+
+// ioctl(fd, EVIOCGBIT(ev, nbytes), buf) as per linux/input.h; nbytes
+// should comfortably cover the highest bit number that will be tested
+// with testBit.
+func ioctlEviocgbit(fd uintptr, ev int, nbytes int) ([]byte, error) {
+	buf := make([]byte, nbytes)
+	req := iocEviocgbit(ev, nbytes)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(&buf[0])))
+	if errno != 0 {
+		return nil, errno
+	}
+	return buf, nil
+}
+
+// iocEviocgbit builds the EVIOCGBIT(ev, len) ioctl request number, as
+// defined by the asm-generic _IOC/_IOR macros: _IOR('E', 0x20 + ev, len).
+func iocEviocgbit(ev int, length int) uintptr {
+	const (
+		iocRead      = 2
+		iocTypeShift = 8
+		iocSizeShift = 16
+		iocDirShift  = 30
+	)
+	return uintptr(iocRead)<<iocDirShift |
+		uintptr('E')<<iocTypeShift |
+		uintptr(0x20+ev) |
+		uintptr(length)<<iocSizeShift
+}
+
+func testBit(bits []byte, bit int) bool {
+	i := bit / 8
+	if i >= len(bits) {
+		return false
+	}
+	return bits[i]&(1<<uint(bit%8)) != 0
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package gpmctl
+
+import "fmt"
+
+// NewFromEvdev is only implemented on linux, since evdev itself is a
+// Linux kernel interface. On every other platform it just errors, so
+// New's gpmctl-then-evdev fallback and the rest of the module still
+// build and link everywhere.
+func NewFromEvdev(paths ...string) (*GPM, error) {
+	return nil, fmt.Errorf("gpmctl: evdev is only supported on linux")
+}
@@ -0,0 +1,85 @@
+//go:build linux
+
+package gpmctl
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestEvdevSource(threshold time.Duration) *evdevSource {
+	return &evdevSource{
+		clickThreshold: threshold,
+		lastDownAt:     map[Buttons]time.Time{},
+		streak:         map[Buttons]int32{},
+	}
+}
+
+func TestEvdevSourceClickStreak(t *testing.T) {
+	s := newTestEvdevSource(50 * time.Millisecond)
+
+	e1 := s.synReport(0, 0, 0, 0, B_LEFT, B_NONE)
+	if e1.Clicks != 1 || e1.Type&SINGLE == 0 {
+		t.Fatalf("first down: Clicks=%d Type=%v, want 1/SINGLE", e1.Clicks, e1.Type)
+	}
+
+	e2 := s.synReport(0, 0, 0, 0, B_LEFT, B_NONE)
+	if e2.Clicks != 2 || e2.Type&DOUBLE == 0 {
+		t.Fatalf("second down within window: Clicks=%d Type=%v, want 2/DOUBLE", e2.Clicks, e2.Type)
+	}
+
+	e3 := s.synReport(0, 0, 0, 0, B_LEFT, B_NONE)
+	if e3.Clicks != 3 || e3.Type&TRIPLE == 0 {
+		t.Fatalf("third down within window: Clicks=%d Type=%v, want 3/TRIPLE", e3.Clicks, e3.Type)
+	}
+
+	e4 := s.synReport(0, 0, 0, 0, B_LEFT, B_NONE)
+	if e4.Clicks != 3 || e4.Type&TRIPLE == 0 {
+		t.Fatalf("fourth down within window: Clicks=%d Type=%v, want capped at 3/TRIPLE", e4.Clicks, e4.Type)
+	}
+}
+
+func TestEvdevSourceClickStreakResetsAfterWindow(t *testing.T) {
+	s := newTestEvdevSource(5 * time.Millisecond)
+
+	s.synReport(0, 0, 0, 0, B_LEFT, B_NONE)
+	time.Sleep(20 * time.Millisecond)
+	e := s.synReport(0, 0, 0, 0, B_LEFT, B_NONE)
+	if e.Clicks != 1 || e.Type&SINGLE == 0 {
+		t.Fatalf("down after window elapsed: Clicks=%d Type=%v, want 1/SINGLE", e.Clicks, e.Type)
+	}
+}
+
+func TestEvdevSourceClickStreakIsPerButton(t *testing.T) {
+	s := newTestEvdevSource(50 * time.Millisecond)
+
+	s.synReport(0, 0, 0, 0, B_LEFT, B_NONE)
+	s.synReport(0, 0, 0, 0, B_LEFT, B_NONE)
+	e := s.synReport(0, 0, 0, 0, B_RIGHT, B_NONE)
+	if e.Clicks != 1 || e.Type&SINGLE == 0 {
+		t.Fatalf("first down of a different button: Clicks=%d Type=%v, want 1/SINGLE", e.Clicks, e.Type)
+	}
+}
+
+func TestEvdevSourceMoveAndDrag(t *testing.T) {
+	s := newTestEvdevSource(50 * time.Millisecond)
+
+	move := s.synReport(3, -2, 0, 0, B_NONE, B_NONE)
+	if move.Type != MOVE || move.X != 3 || move.Y != -2 {
+		t.Fatalf("plain move: got %+v", move)
+	}
+
+	s.synReport(0, 0, 0, 0, B_LEFT, B_NONE) // button down
+	s.buttons = B_LEFT                      // Read's evKey handling would have set this
+	drag := s.synReport(1, 1, 0, 0, B_NONE, B_NONE)
+	if drag.Type != DRAG {
+		t.Fatalf("move while button held: Type=%v, want DRAG", drag.Type)
+	}
+}
+
+func TestNativeInputEventSize(t *testing.T) {
+	size := nativeInputEventSize()
+	if size != inputEventSize32 && size != inputEventSize64 {
+		t.Fatalf("nativeInputEventSize() = %d, want %d or %d", size, inputEventSize32, inputEventSize64)
+	}
+}
@@ -8,35 +8,36 @@
 //
 // example:
 //
-//   package main
+//	package main
 //
-//   import (
-//   	"log"
+//	import (
+//		"log"
 //
-//   	gpmctl "github.com/jackdoe/go-gpmctl"
-//   )
+//		gpmctl "github.com/jackdoe/go-gpmctl"
+//	)
 //
-//   func main() {
-//   	g, err := gpmctl.NewGPM(gpmctl.DefaultConf)
-//   	if err != nil {
-//   		panic(err)
-//   	}
-//   	for {
-//   		event, err := g.Read()
-//   		if err != nil {
-//   			panic(err)
-//   		}
+//	func main() {
+//		g, err := gpmctl.NewGPM(gpmctl.DefaultConf)
+//		if err != nil {
+//			panic(err)
+//		}
+//		for {
+//			event, err := g.Read()
+//			if err != nil {
+//				panic(err)
+//			}
 //
-//   		log.Printf("%s", event)
-//   	}
-//   }
+//			log.Printf("%s", event)
+//		}
+//	}
 //
 //
-//   ..
-//   2020/03/16 23:18:57 type:move[buttons:, modifiers:0, vc:4] x:190[dx:0] y:28[dy:1], clicks:0 margin:, wdx:0, wdy:0
-//   2020/03/16 23:18:57 type:move[buttons:, modifiers:0, vc:4] x:189[dx:-1] y:28[dy:0], clicks:0 margin:, wdx:0, wdy:0
-//   2020/03/16 23:18:57 type:down,single[buttons:, modifiers:0, vc:4] x:189[dx:0] y:28[dy:0], clicks:0 margin:, wdx:0, wdy:0
-//   2020/03/16 23:18:57 type:drag,single,mflag[buttons:, modifiers:0, vc:4] x:189[dx:0] y:29[dy:1], clicks:0 margin:, wdx:0, wdy:0
+//	..
+//	2020/03/16 23:18:57 type:move[buttons:, modifiers:0, vc:4] x:190[dx:0] y:28[dy:1], clicks:0 margin:, wdx:0, wdy:0
+//	2020/03/16 23:18:57 type:move[buttons:, modifiers:0, vc:4] x:189[dx:-1] y:28[dy:0], clicks:0 margin:, wdx:0, wdy:0
+//	2020/03/16 23:18:57 type:down,single[buttons:, modifiers:0, vc:4] x:189[dx:0] y:28[dy:0], clicks:0 margin:, wdx:0, wdy:0
+//	2020/03/16 23:18:57 type:drag,single,mflag[buttons:, modifiers:0, vc:4] x:189[dx:0] y:29[dy:1], clicks:0 margin:, wdx:0, wdy:0
+//
 // ..
 package gpmctl
 
@@ -48,6 +49,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 	"unsafe"
 )
@@ -149,25 +151,25 @@ func (b Buttons) String() string {
 
 // Gpm Event Type - as per gpm.h
 //
-//  enum Gpm_Etype {
-//    GPM_MOVE=1,
-//    GPM_DRAG=2,   /* exactly one of the bare ones is active at a time */
-//    GPM_DOWN=4
-//    GPM_UP=  8,
+//	enum Gpm_Etype {
+//	  GPM_MOVE=1,
+//	  GPM_DRAG=2,   /* exactly one of the bare ones is active at a time */
+//	  GPM_DOWN=4
+//	  GPM_UP=  8,
 //
-//  #define GPM_BARE_EVENTS(type) ((type)&(0x0f|GPM_ENTER|GPM_LEAVE))
+//	#define GPM_BARE_EVENTS(type) ((type)&(0x0f|GPM_ENTER|GPM_LEAVE))
 //
-//    GPM_SINGLE=16,            /* at most one in three is set */
-//    GPM_DOUBLE=32,
-//    GPM_TRIPLE=64,            /* WARNING: I depend on the values */
+//	  GPM_SINGLE=16,            /* at most one in three is set */
+//	  GPM_DOUBLE=32,
+//	  GPM_TRIPLE=64,            /* WARNING: I depend on the values */
 //
-//    GPM_MFLAG=128,            /* motion during click? */
-//    GPM_HARD=256,             /* if set in the defaultMask, force an already
-//                     used event to pass over to another handler */
+//	  GPM_MFLAG=128,            /* motion during click? */
+//	  GPM_HARD=256,             /* if set in the defaultMask, force an already
+//	                   used event to pass over to another handler */
 //
-//    GPM_ENTER=512,            /* enter event, user in Roi's */
-//    GPM_LEAVE=1024            /* leave event, used in Roi's */
-//  };
+//	  GPM_ENTER=512,            /* enter event, user in Roi's */
+//	  GPM_LEAVE=1024            /* leave event, used in Roi's */
+//	};
 type EventType uint16
 
 const (
@@ -231,7 +233,7 @@ func (e EventType) String() string {
 
 // Gpm Margin Enum as per gpm.h
 //
-//   enum Gpm_Margin {GPM_TOP=1, GPM_BOT=2, GPM_LFT=4, GPM_RGT=8};
+//	enum Gpm_Margin {GPM_TOP=1, GPM_BOT=2, GPM_LFT=4, GPM_RGT=8};
 type Margin int
 
 const (
@@ -260,22 +262,22 @@ func (m Margin) String() string {
 
 // Event defined as per gpm.h
 //
-//  typedef struct Gpm_Event {
-//    unsigned char buttons, modifiers;  /* try to be a multiple of 4 */
-//    unsigned short vc;
-//    short dx, dy, x, y; /* displacement x,y for this event, and absolute x,y */
-//    enum Gpm_Etype type;
-//    /* clicks e.g. double click are determined by time-based processing */
-//    int clicks;
-//    enum Gpm_Margin margin;
-//    /* wdx/y: displacement of wheels in this event. Absolute values are not
-//     * required, because wheel movement is typically used for scrolling
-//     * or selecting fields, not for cursor positioning. The application
-//     * can determine when the end of file or form is reached, and not
-//     * go any further.
-//     * A single mouse will use wdy, "vertical scroll" wheel. */
-//    short wdx, wdy;
-//  } Gpm_Event;
+//	typedef struct Gpm_Event {
+//	  unsigned char buttons, modifiers;  /* try to be a multiple of 4 */
+//	  unsigned short vc;
+//	  short dx, dy, x, y; /* displacement x,y for this event, and absolute x,y */
+//	  enum Gpm_Etype type;
+//	  /* clicks e.g. double click are determined by time-based processing */
+//	  int clicks;
+//	  enum Gpm_Margin margin;
+//	  /* wdx/y: displacement of wheels in this event. Absolute values are not
+//	   * required, because wheel movement is typically used for scrolling
+//	   * or selecting fields, not for cursor positioning. The application
+//	   * can determine when the end of file or form is reached, and not
+//	   * go any further.
+//	   * A single mouse will use wdy, "vertical scroll" wheel. */
+//	  short wdx, wdy;
+//	} Gpm_Event;
 type Event struct {
 	Buttons   Buttons
 	Modifiers uint8
@@ -307,23 +309,64 @@ func (event Event) String() string {
 
 // GPM connection
 type GPM struct {
-	c   net.Conn
+	src Source
 	tty int
 	pid int
+
+	rs      *regions
+	evMu    sync.Mutex
+	events  chan RegionEvent
+	handler EventHandler
+}
+
+// regions lazily creates the ROI tracker, so GPM's that never call
+// AddRegion/Dispatch don't pay for it.
+func (g *GPM) regions() *regions {
+	if g.rs == nil {
+		g.rs = newRegions()
+	}
+	return g.rs
+}
+
+// eventsChan lazily creates the channel backing Events(). Dispatch must
+// not use it to decide whether to send: use hasEventsConsumer instead,
+// since eventsChan always returns a non-nil channel.
+func (g *GPM) eventsChan() chan RegionEvent {
+	g.evMu.Lock()
+	defer g.evMu.Unlock()
+	if g.events == nil {
+		g.events = make(chan RegionEvent)
+	}
+	return g.events
+}
+
+// hasEventsConsumer reports whether Events() was ever called, i.e.
+// whether anyone is expected to be reading from the channel Dispatch
+// would send on.
+func (g *GPM) hasEventsConsumer() bool {
+	g.evMu.Lock()
+	defer g.evMu.Unlock()
+	return g.events != nil
 }
 
 // Struct sent via the socket after connecting
-//   typedef struct Gpm_Connect {
-//     unsigned short eventMask, defaultMask; // 4
-//     unsigned short minMod, maxMod;         // 4
-//     int pid;                               // 4
-//     int vc;                                // 4
-//   } Gpm_Connect;
+//
+//	typedef struct Gpm_Connect {
+//	  unsigned short eventMask, defaultMask; // 4
+//	  unsigned short minMod, maxMod;         // 4
+//	  int pid;                               // 4
+//	  int vc;                                // 4
+//	} Gpm_Connect;
 type GPMConnect struct {
 	EventMask   EventType
 	DefaultMask EventType
 	MinMod      uint16
 	MaxMod      uint16
+
+	// Magic makes Read expect every event to be prefixed on the wire
+	// with the 4 byte GPM_MAGIC word, as emitted by gpm builds compiled
+	// with magic-prefixed protocol framing.
+	Magic bool
 }
 
 var DefaultConf = GPMConnect{
@@ -362,37 +405,16 @@ func NewGPM(conf GPMConnect) (*GPM, error) {
 		c.Close()
 		return nil, err
 	}
-	return &GPM{c: c, tty: tty, pid: pid}, nil
+	return &GPM{src: &gpmctlSource{c: c, magic: conf.Magic}, tty: tty, pid: pid}, nil
 }
 
 // Reads one event mouse, or blocks if there are no events
 // NB: some gpm's could have `#define GPM_MAGIC 0x47706D4C` in every message, at the moment that is not supported
 func (g *GPM) Read() (Event, error) {
-	// sizeof Gpm_Event, this assumes sizeof Gpm_EventType to be 4
-	// bytes and sizeof Margin to be 4 bytes, which is not guaranteed
-	b := make([]byte, 28)
-	_, err := g.c.Read(b)
-	if err != nil {
-		return Event{}, err
-	}
-	e := Event{
-		Buttons:   Buttons(uint8(nativeEndian.Uint16(b[0:]))),
-		Modifiers: uint8(nativeEndian.Uint16(b[1:])),
-		VC:        nativeEndian.Uint16(b[2:]),
-		DX:        int16(nativeEndian.Uint16(b[4:])),
-		DY:        int16(nativeEndian.Uint16(b[6:])),
-		X:         int16(nativeEndian.Uint16(b[8:])),
-		Y:         int16(nativeEndian.Uint16(b[10:])),
-		Type:      EventType(nativeEndian.Uint32(b[12:])),
-		Clicks:    int32(nativeEndian.Uint32(b[16:])),
-		Margin:    Margin(nativeEndian.Uint32(b[20:])),
-		WDX:       int16(nativeEndian.Uint16(b[24:])),
-		WDY:       int16(nativeEndian.Uint16(b[26:])),
-	}
-	return e, nil
+	return g.src.Read()
 }
 
 // close the gpm connection
 func (g *GPM) Close() {
-	g.c.Close()
+	g.src.Close()
 }
@@ -0,0 +1,187 @@
+package gpmctl
+
+import "sync"
+
+// Region of interest, the Go equivalent of the Roi's gpm itself uses
+// internally to decide which window/button owns a click (see gpm's
+// lib_roi.c). Coordinates are inclusive and in the same space as
+// Event.X/Event.Y.
+//
+// Mask restricts which EventType's are delivered for this region, e.g. a
+// region only interested in clicks can set Mask: DOWN|UP|DRAG. A zero
+// Mask means ANY, i.e. every event type is delivered. The synthetic
+// ENTER/LEAVE events are delivered regardless of Mask.
+type Region struct {
+	ID     int
+	X1, Y1 int16
+	X2, Y2 int16
+	Mask   EventType
+}
+
+func (r Region) contains(x, y int16) bool {
+	return x >= r.X1 && x <= r.X2 && y >= r.Y1 && y <= r.Y2
+}
+
+func (r Region) wants(t EventType) bool {
+	if r.Mask == 0 {
+		return true
+	}
+	return r.Mask&t > 0
+}
+
+// RegionEvent is delivered for every raw Event that falls inside a
+// registered Region and passes its Mask, plus the synthetic ENTER/LEAVE events generated when
+// the pointer moves between regions.
+type RegionEvent struct {
+	Event
+	RegionID int
+}
+
+// EventHandler is called by Dispatch for every RegionEvent, in addition
+// to (or instead of) reading from Events().
+type EventHandler func(RegionEvent)
+
+// regions tracks the registered Region's and which one contained the
+// previous (X,Y), so Dispatch can synthesize ENTER/LEAVE the way gpm's
+// lib_roi does for mouse-driven menus.
+type regions struct {
+	mu      sync.Mutex
+	list    []Region // last added is top of the z-order
+	nextID  int
+	current int // ID of the region containing the previous x,y, or -1
+}
+
+func newRegions() *regions {
+	return &regions{nextID: 1, current: -1}
+}
+
+// add registers r, assigning it an ID if it doesn't have one, and puts it
+// on top of the z-order.
+func (rs *regions) add(r Region) int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if r.ID == 0 {
+		r.ID = rs.nextID
+	}
+	if r.ID >= rs.nextID {
+		rs.nextID = r.ID + 1
+	}
+	rs.list = append(rs.list, r)
+	return r.ID
+}
+
+// remove unregisters the region with the given id, if any.
+func (rs *regions) remove(id int) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for i, r := range rs.list {
+		if r.ID == id {
+			rs.list = append(rs.list[:i], rs.list[i+1:]...)
+			break
+		}
+	}
+	if rs.current == id {
+		rs.current = -1
+	}
+}
+
+// hitTest returns the top-most (last-added-wins) region containing (x,y),
+// or nil if none matches.
+func (rs *regions) hitTest(x, y int16) *Region {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for i := len(rs.list) - 1; i >= 0; i-- {
+		if rs.list[i].contains(x, y) {
+			r := rs.list[i]
+			return &r
+		}
+	}
+	return nil
+}
+
+// dispatch updates the current region for event and returns, in order,
+// the RegionEvent's that should be delivered for it: an optional LEAVE
+// for the region the pointer just left, an optional ENTER for the region
+// it just entered, and the underlying event itself if the (possibly new)
+// current region wants it.
+func (rs *regions) dispatch(event Event) []RegionEvent {
+	hit := rs.hitTest(event.X, event.Y)
+
+	rs.mu.Lock()
+	prev := rs.current
+	next := -1
+	if hit != nil {
+		next = hit.ID
+	}
+	rs.current = next
+	rs.mu.Unlock()
+
+	out := []RegionEvent{}
+	if prev != next && prev != -1 {
+		out = append(out, RegionEvent{Event: withType(event, LEAVE), RegionID: prev})
+	}
+	if prev != next && next != -1 {
+		out = append(out, RegionEvent{Event: withType(event, ENTER), RegionID: next})
+	}
+	if hit != nil && hit.wants(event.Type) {
+		out = append(out, RegionEvent{Event: event, RegionID: hit.ID})
+	}
+	return out
+}
+
+func withType(event Event, t EventType) Event {
+	event.Type = t
+	return event
+}
+
+// AddRegion registers a clickable area the way ncurses' lib_mouse
+// registers button/window hit-boxes. It returns the Region's ID, which
+// may be used with RemoveRegion. Regions are z-ordered: the most
+// recently added region wins when areas overlap.
+func (g *GPM) AddRegion(r Region) int {
+	return g.regions().add(r)
+}
+
+// RemoveRegion unregisters the region previously returned by AddRegion.
+func (g *GPM) RemoveRegion(id int) {
+	g.regions().remove(id)
+}
+
+// OnEvent registers h to be called, from Dispatch, for every RegionEvent.
+func (g *GPM) OnEvent(h EventHandler) {
+	g.handler = h
+}
+
+// Events returns the channel Dispatch publishes RegionEvent's to.
+func (g *GPM) Events() <-chan RegionEvent {
+	return g.eventsChan()
+}
+
+// Dispatch reads events in a loop via Read, tracking the region
+// containing the pointer and synthesizing ENTER/LEAVE before delivering
+// the underlying event, then publishes the result to Events() and to the
+// handler registered with OnEvent, if any. It returns when Read fails,
+// typically because the connection was closed.
+//
+// A RegionEvent is only sent on the Events() channel if something has
+// actually called Events(); a caller using OnEvent alone, as documented
+// there, never blocks waiting for a channel nobody reads from.
+func (g *GPM) Dispatch() error {
+	for {
+		event, err := g.Read()
+		if err != nil {
+			return err
+		}
+		for _, re := range g.regions().dispatch(event) {
+			if g.handler != nil {
+				g.handler(re)
+			}
+			if g.hasEventsConsumer() {
+				g.eventsChan() <- re
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package gpmctl
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRegionsHitTestZOrder(t *testing.T) {
+	rs := newRegions()
+	bottom := rs.add(Region{X1: 0, Y1: 0, X2: 10, Y2: 10})
+	top := rs.add(Region{X1: 5, Y1: 5, X2: 8, Y2: 8})
+
+	hit := rs.hitTest(6, 6)
+	if hit == nil || hit.ID != top {
+		t.Fatalf("overlap should hit the most recently added region, got %+v, want ID=%d", hit, top)
+	}
+
+	hit = rs.hitTest(1, 1)
+	if hit == nil || hit.ID != bottom {
+		t.Fatalf("outside the overlap should hit the bottom region, got %+v, want ID=%d", hit, bottom)
+	}
+
+	if hit := rs.hitTest(20, 20); hit != nil {
+		t.Fatalf("outside every region should miss, got %+v", hit)
+	}
+}
+
+func TestRegionsRemove(t *testing.T) {
+	rs := newRegions()
+	id := rs.add(Region{X1: 0, Y1: 0, X2: 10, Y2: 10})
+
+	rs.remove(id)
+	if hit := rs.hitTest(5, 5); hit != nil {
+		t.Fatalf("removed region should no longer hit, got %+v", hit)
+	}
+}
+
+func TestRegionsDispatchEnterLeave(t *testing.T) {
+	rs := newRegions()
+	a := rs.add(Region{X1: 0, Y1: 0, X2: 10, Y2: 10})
+	b := rs.add(Region{X1: 20, Y1: 20, X2: 30, Y2: 30})
+
+	out := rs.dispatch(Event{Type: MOVE, X: 5, Y: 5})
+	if len(out) != 2 || out[0].Type != ENTER || out[0].RegionID != a || out[1].RegionID != a {
+		t.Fatalf("first move into region a: got %+v, want [ENTER(a), MOVE(a)]", out)
+	}
+
+	out = rs.dispatch(Event{Type: MOVE, X: 25, Y: 25})
+	if len(out) != 3 || out[0].Type != LEAVE || out[0].RegionID != a || out[1].Type != ENTER || out[1].RegionID != b {
+		t.Fatalf("crossing from a to b: got %+v, want [LEAVE(a), ENTER(b), MOVE(b)]", out)
+	}
+
+	out = rs.dispatch(Event{Type: MOVE, X: 100, Y: 100})
+	if len(out) != 1 || out[0].Type != LEAVE || out[0].RegionID != b {
+		t.Fatalf("leaving every region: got %+v, want [LEAVE(b)]", out)
+	}
+}
+
+func TestRegionsDispatchRespectsMask(t *testing.T) {
+	rs := newRegions()
+	id := rs.add(Region{X1: 0, Y1: 0, X2: 10, Y2: 10, Mask: DOWN | UP})
+
+	out := rs.dispatch(Event{Type: MOVE, X: 5, Y: 5})
+	if len(out) != 1 || out[0].Type != ENTER {
+		t.Fatalf("move into a region masked to DOWN|UP: got %+v, want only the synthetic ENTER", out)
+	}
+
+	out = rs.dispatch(Event{Type: DOWN, X: 5, Y: 5})
+	if len(out) != 1 || out[0].Type != DOWN || out[0].RegionID != id {
+		t.Fatalf("down inside the same region: got %+v, want [DOWN]", out)
+	}
+}
+
+// fakeSource feeds a fixed Event then errors, so Dispatch returns after
+// exactly one iteration.
+type fakeSource struct {
+	event Event
+	sent  bool
+}
+
+func (f *fakeSource) Read() (Event, error) {
+	if f.sent {
+		return Event{}, io.EOF
+	}
+	f.sent = true
+	return f.event, nil
+}
+
+func (f *fakeSource) Close() error { return nil }
+
+// A caller that only registers OnEvent and never calls Events() must not
+// deadlock on the unbuffered channel Events() would otherwise back.
+func TestGPMDispatchHandlerOnlyDoesNotBlock(t *testing.T) {
+	var got RegionEvent
+	g := &GPM{src: &fakeSource{event: Event{Type: MOVE, X: 1, Y: 1}}}
+	g.AddRegion(Region{X1: 0, Y1: 0, X2: 100, Y2: 100})
+	g.OnEvent(func(re RegionEvent) { got = re })
+
+	done := make(chan error, 1)
+	go func() { done <- g.Dispatch() }()
+
+	select {
+	case err := <-done:
+		if err != io.EOF {
+			t.Fatalf("Dispatch returned %v, want io.EOF", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Dispatch blocked sending on Events() with no consumer")
+	}
+
+	if got.RegionID == 0 {
+		t.Fatalf("handler was never called")
+	}
+}
@@ -0,0 +1,93 @@
+package gpmctl
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+)
+
+// Source is anything GPM can pull Event's from: the gpm daemon's control
+// socket, or a raw evdev device when gpm isn't running.
+type Source interface {
+	Read() (Event, error)
+	Close() error
+}
+
+// GPM_MAGIC as per gpm.h: some gpm builds prefix every Gpm_Event on the
+// wire with this little-endian magic word.
+const gpmMagic uint32 = 0x47706D4C
+
+// gpmctlSource reads Gpm_Event's off the /dev/gpmctl unix socket, as
+// NewGPM always did before Source existed.
+type gpmctlSource struct {
+	c     net.Conn
+	magic bool
+}
+
+func (s *gpmctlSource) Read() (Event, error) {
+	if s.magic {
+		if err := s.syncMagic(); err != nil {
+			return Event{}, err
+		}
+	}
+
+	// sizeof Gpm_Event, this assumes sizeof Gpm_EventType to be 4
+	// bytes and sizeof Margin to be 4 bytes, which is not guaranteed
+	b := make([]byte, 28)
+	if _, err := io.ReadFull(s.c, b); err != nil {
+		return Event{}, err
+	}
+	e := Event{
+		Buttons:   Buttons(b[0]),
+		Modifiers: b[1],
+		VC:        nativeEndian.Uint16(b[2:]),
+		DX:        int16(nativeEndian.Uint16(b[4:])),
+		DY:        int16(nativeEndian.Uint16(b[6:])),
+		X:         int16(nativeEndian.Uint16(b[8:])),
+		Y:         int16(nativeEndian.Uint16(b[10:])),
+		Type:      EventType(nativeEndian.Uint32(b[12:])),
+		Clicks:    int32(nativeEndian.Uint32(b[16:])),
+		Margin:    Margin(nativeEndian.Uint32(b[20:])),
+		WDX:       int16(nativeEndian.Uint16(b[24:])),
+		WDY:       int16(nativeEndian.Uint16(b[26:])),
+	}
+	return e, nil
+}
+
+// syncMagic reads the 4 byte GPM_MAGIC word that precedes each event on
+// magic-prefixed gpm builds. If it doesn't match, it resynchronizes by
+// sliding one byte at a time through the stream until it does, the way a
+// framed protocol reader recovers from a torn/previous read.
+func (s *gpmctlSource) syncMagic() error {
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(s.c, b); err != nil {
+		return err
+	}
+	for binary.LittleEndian.Uint32(b) != gpmMagic {
+		copy(b, b[1:])
+		if _, err := io.ReadFull(s.c, b[3:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *gpmctlSource) Close() error {
+	return s.c.Close()
+}
+
+func (s *gpmctlSource) SetReadDeadline(t time.Time) error {
+	return s.c.SetReadDeadline(t)
+}
+
+// New connects to the gpm daemon via NewGPM, and if that fails (no gpm
+// running, or /dev/gpmctl doesn't exist) falls back to reading raw mouse
+// events straight off /dev/input/eventX via NewFromEvdev.
+func New(conf GPMConnect) (*GPM, error) {
+	g, err := NewGPM(conf)
+	if err == nil {
+		return g, nil
+	}
+	return NewFromEvdev()
+}
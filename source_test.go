@@ -0,0 +1,95 @@
+package gpmctl
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestGpmctlSourceMagicResync(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	src := &gpmctlSource{c: server, magic: true}
+
+	body := make([]byte, 28)
+	body[0] = byte(B_LEFT)
+	binary.LittleEndian.PutUint16(body[2:], 7) // VC
+
+	go func() {
+		frame := make([]byte, 0, 3+4+28)
+		frame = append(frame, 0xDE, 0xAD, 0xBE) // garbage preceding the first real magic word
+		magic := make([]byte, 4)
+		binary.LittleEndian.PutUint32(magic, gpmMagic)
+		frame = append(frame, magic...)
+		frame = append(frame, body...)
+		client.Write(frame)
+	}()
+
+	event, err := src.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if event.Buttons != B_LEFT {
+		t.Fatalf("Buttons = %v, want %v", event.Buttons, B_LEFT)
+	}
+	if event.VC != 7 {
+		t.Fatalf("VC = %v, want 7", event.VC)
+	}
+}
+
+func TestGpmctlSourceNoMagicIgnoresFraming(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	src := &gpmctlSource{c: server}
+
+	body := make([]byte, 28)
+	body[0] = byte(B_RIGHT)
+
+	go func() { client.Write(body) }()
+
+	event, err := src.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if event.Buttons != B_RIGHT {
+		t.Fatalf("Buttons = %v, want %v", event.Buttons, B_RIGHT)
+	}
+}
+
+func TestGpmctlSourceDecodeOffsets(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	src := &gpmctlSource{c: server}
+
+	var wantDX int16 = -3
+
+	b := make([]byte, 28)
+	b[0] = byte(B_LEFT)
+	b[1] = 0x05                                         // Modifiers
+	nativeEndian.PutUint16(b[2:], 42)                   // VC
+	nativeEndian.PutUint16(b[4:], uint16(wantDX))       // DX
+	nativeEndian.PutUint32(b[12:], uint32(DOWN|SINGLE)) // Type
+	nativeEndian.PutUint32(b[16:], 1)                   // Clicks
+
+	go func() { client.Write(b) }()
+
+	event, err := src.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if event.Modifiers != 0x05 {
+		t.Fatalf("Modifiers = %v, want 5 (must not overlap VC)", event.Modifiers)
+	}
+	if event.VC != 42 {
+		t.Fatalf("VC = %v, want 42", event.VC)
+	}
+	if event.DX != wantDX {
+		t.Fatalf("DX = %v, want %v", event.DX, wantDX)
+	}
+}
@@ -0,0 +1,137 @@
+package xtermbridge
+
+import (
+	"bytes"
+	"testing"
+
+	gpmctl "github.com/jackdoe/go-gpmctl"
+)
+
+func TestEncodeX10PressAndRelease(t *testing.T) {
+	press := encodeX10(btnLeft, true, 10, 20)
+	want := []byte{0x1b, '[', 'M', byte(32 + btnLeft), byte(10 + 1 + 32), byte(20 + 1 + 32)}
+	if !bytes.Equal(press, want) {
+		t.Fatalf("press = %v, want %v", press, want)
+	}
+
+	release := encodeX10(btnLeft, false, 10, 20)
+	wantRelease := []byte{0x1b, '[', 'M', byte(32 + btnNone), byte(10 + 1 + 32), byte(20 + 1 + 32)}
+	if !bytes.Equal(release, wantRelease) {
+		t.Fatalf("release = %v, want %v (button code must always be 3 on release)", release, wantRelease)
+	}
+}
+
+func TestEncodeX10ClampsLargeCoordinates(t *testing.T) {
+	seq := encodeX10(btnLeft, true, 1000, 1000)
+	if seq[4] != 255 || seq[5] != 255 {
+		t.Fatalf("got %v, want coordinate bytes clamped to 255", seq)
+	}
+}
+
+func TestEncodeSGRPressAndRelease(t *testing.T) {
+	press := string(encodeSGR(btnLeft, 10, 20, true))
+	if press != "\x1b[<0;11;21M" {
+		t.Fatalf("press = %q, want %q", press, "\x1b[<0;11;21M")
+	}
+
+	release := string(encodeSGR(btnLeft, 10, 20, false))
+	if release != "\x1b[<0;11;21m" {
+		t.Fatalf("release = %q, want %q", release, "\x1b[<0;11;21m")
+	}
+}
+
+func TestClassifyWheel(t *testing.T) {
+	b := &Bridge{Mode: SGR1006}
+
+	code, press, ok := b.classify(gpmctl.Event{WDY: 1})
+	if !ok || code != btnWheelUp || !press {
+		t.Fatalf("wheel up: code=%d press=%v ok=%v", code, press, ok)
+	}
+
+	code, _, ok = b.classify(gpmctl.Event{WDY: -1})
+	if !ok || code != btnWheelDn {
+		t.Fatalf("wheel down: code=%d ok=%v", code, ok)
+	}
+}
+
+func TestClassifyButtonDownAndUp(t *testing.T) {
+	b := &Bridge{Mode: SGR1006}
+
+	down := gpmctl.Event{Buttons: gpmctl.B_RIGHT, Type: gpmctl.DOWN}
+	code, press, ok := b.classify(down)
+	if !ok || code != btnRight || !press {
+		t.Fatalf("button down: code=%d press=%v ok=%v", code, press, ok)
+	}
+
+	up := gpmctl.Event{Buttons: gpmctl.B_RIGHT, Type: gpmctl.UP}
+	code, press, ok = b.classify(up)
+	if !ok || code != btnRight || press {
+		t.Fatalf("button up: code=%d press=%v ok=%v", code, press, ok)
+	}
+}
+
+// This is the scenario the gpmctl package doc's own recorded sample
+// shows: "down,single[buttons:, ...]" and the matching release both
+// carry Buttons==B_NONE. The bridge must still identify and forward the
+// release instead of dropping it (which would leave the terminal
+// thinking a button is stuck down).
+func TestClassifyDownAndUpWithEmptyButtons(t *testing.T) {
+	b := &Bridge{Mode: SGR1006}
+
+	down := gpmctl.Event{Type: gpmctl.DOWN | gpmctl.SINGLE}
+	if _, press, ok := b.classify(down); !ok || !press {
+		t.Fatalf("down with empty Buttons: press=%v ok=%v, want forwarded press", press, ok)
+	}
+
+	up := gpmctl.Event{Type: gpmctl.UP | gpmctl.SINGLE}
+	code, press, ok := b.classify(up)
+	if !ok {
+		t.Fatalf("up with empty Buttons was dropped, want it forwarded using the latched button")
+	}
+	if press {
+		t.Fatalf("up with empty Buttons: press=%v, want release", press)
+	}
+	if code != btnLeft {
+		t.Fatalf("up code=%d, want %d (SGR1006 keeps the latched button on release, only X10/Normal force code 3)", code, btnLeft)
+	}
+}
+
+func TestClassifyDragWithEmptyButtonsUsesLatch(t *testing.T) {
+	b := &Bridge{Mode: SGR1006, ReportMotion: true}
+
+	if _, _, ok := b.classify(gpmctl.Event{Type: gpmctl.DOWN | gpmctl.SINGLE}); !ok {
+		t.Fatalf("setup down was dropped")
+	}
+
+	drag := gpmctl.Event{Type: gpmctl.DRAG | gpmctl.MFLAG}
+	code, press, ok := b.classify(drag)
+	if !ok || !press {
+		t.Fatalf("drag with empty Buttons: code=%d press=%v ok=%v", code, press, ok)
+	}
+	if code != btnLeft+mflagBit {
+		t.Fatalf("drag code=%d, want %d (left, the DOWN fallback, with motion bit set)", code, btnLeft+mflagBit)
+	}
+}
+
+func TestClassifyMotionRequiresReportMotion(t *testing.T) {
+	move := gpmctl.Event{Type: gpmctl.MOVE}
+
+	if _, _, ok := (&Bridge{Mode: SGR1006}).classify(move); ok {
+		t.Fatalf("motion should not be reported when ReportMotion is false")
+	}
+	if _, _, ok := (&Bridge{Mode: Normal, ReportMotion: true}).classify(move); ok {
+		t.Fatalf("motion should never be reported in Normal mode")
+	}
+	if _, _, ok := (&Bridge{Mode: SGR1006, ReportMotion: true}).classify(move); !ok {
+		t.Fatalf("motion should be reported in SGR1006 mode with ReportMotion true")
+	}
+}
+
+func TestClassifyDragSetsMotionFlag(t *testing.T) {
+	b := &Bridge{Mode: SGR1006, ReportMotion: true}
+	drag := gpmctl.Event{Buttons: gpmctl.B_LEFT, Type: gpmctl.DRAG | gpmctl.MFLAG}
+	code, _, ok := b.classify(drag)
+	if !ok || code != btnLeft+mflagBit {
+		t.Fatalf("drag: code=%d ok=%v, want %d", code, ok, btnLeft+mflagBit)
+	}
+}